@@ -0,0 +1,73 @@
+package source
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Coordinates contains the minimal information needed to describe how to find a file within any possible
+// source object (e.g. an image layer or a directory on disk). Unlike a file.Reference, which is only
+// meaningful relative to the filetree that produced it, Coordinates are stable across scans of the same
+// source, making them suitable for referencing files from downstream SBOM formats (e.g. CycloneDX, SPDX).
+type Coordinates struct {
+	RealPath     string `json:"path"`              // The path where all path ancestors have been fully resolved
+	FileSystemID string `json:"layerID,omitempty"` // An ID representing the filesystem (e.g. a layer digest). Empty for sources with a single filesystem, such as a directory.
+}
+
+// ID returns a deterministic identifier for these coordinates, suitable for use as a map key or for
+// correlating the same file across independent scans of a source.
+func (c Coordinates) ID() string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(c.RealPath+"+"+c.FileSystemID)))
+}
+
+func (c Coordinates) String() string {
+	str := c.RealPath
+	if c.FileSystemID != "" {
+		str += fmt.Sprintf(" (%s)", c.FileSystemID)
+	}
+	return str
+}
+
+// CoordinateSet is a collection of Coordinates that ensures every member is unique.
+type CoordinateSet struct {
+	set map[Coordinates]struct{}
+}
+
+// NewCoordinateSet returns a new CoordinateSet seeded with the given coordinates.
+func NewCoordinateSet(coordinates ...Coordinates) CoordinateSet {
+	s := CoordinateSet{
+		set: make(map[Coordinates]struct{}),
+	}
+	s.Add(coordinates...)
+	return s
+}
+
+// Add inserts the given coordinates into the set, ignoring any that are already present.
+func (s *CoordinateSet) Add(coordinates ...Coordinates) {
+	if s.set == nil {
+		s.set = make(map[Coordinates]struct{})
+	}
+	for _, c := range coordinates {
+		s.set[c] = struct{}{}
+	}
+}
+
+// Contains indicates whether the given coordinates are already a member of the set.
+func (s CoordinateSet) Contains(c Coordinates) bool {
+	_, ok := s.set[c]
+	return ok
+}
+
+// ToSlice returns the coordinates in the set in no particular order.
+func (s CoordinateSet) ToSlice() []Coordinates {
+	coordinates := make([]Coordinates, 0, len(s.set))
+	for c := range s.set {
+		coordinates = append(coordinates, c)
+	}
+	return coordinates
+}
+
+// Len returns the number of coordinates in the set.
+func (s CoordinateSet) Len() int {
+	return len(s.set)
+}
@@ -0,0 +1,71 @@
+package source
+
+import "testing"
+
+func TestCoordinateSet_AddContainsLen(t *testing.T) {
+	s := NewCoordinateSet()
+	if s.Len() != 0 {
+		t.Fatalf("expected empty set, got len=%d", s.Len())
+	}
+
+	a := Coordinates{RealPath: "/bin/busybox", FileSystemID: "sha256:aaa"}
+	b := Coordinates{RealPath: "/bin/busybox", FileSystemID: "sha256:bbb"}
+
+	s.Add(a)
+	if !s.Contains(a) {
+		t.Fatalf("expected set to contain %v", a)
+	}
+	if s.Contains(b) {
+		t.Fatalf("did not expect set to contain %v", b)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("expected len=1, got %d", s.Len())
+	}
+
+	// adding the same coordinates again is a no-op
+	s.Add(a)
+	if s.Len() != 1 {
+		t.Fatalf("expected len=1 after duplicate add, got %d", s.Len())
+	}
+
+	s.Add(b)
+	if s.Len() != 2 {
+		t.Fatalf("expected len=2, got %d", s.Len())
+	}
+}
+
+func TestCoordinateSet_ToSlice(t *testing.T) {
+	want := []Coordinates{
+		{RealPath: "/etc/passwd"},
+		{RealPath: "/etc/shadow"},
+	}
+
+	s := NewCoordinateSet(want...)
+
+	got := s.ToSlice()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d coordinates, got %d", len(want), len(got))
+	}
+
+	seen := make(map[Coordinates]struct{}, len(got))
+	for _, c := range got {
+		seen[c] = struct{}{}
+	}
+	for _, c := range want {
+		if _, ok := seen[c]; !ok {
+			t.Fatalf("expected %v to be present in ToSlice() result", c)
+		}
+	}
+}
+
+func TestCoordinates_IDStableAndDistinct(t *testing.T) {
+	a := Coordinates{RealPath: "/bin/busybox", FileSystemID: "sha256:aaa"}
+	b := Coordinates{RealPath: "/bin/busybox", FileSystemID: "sha256:bbb"}
+
+	if a.ID() != a.ID() {
+		t.Fatalf("expected ID() to be stable across calls")
+	}
+	if a.ID() == b.ID() {
+		t.Fatalf("expected different filesystem IDs to produce different coordinate IDs")
+	}
+}
@@ -0,0 +1,41 @@
+package source
+
+import (
+	"context"
+	"io"
+)
+
+// FileResolver is the interface all source data types must implement to provide file-access to catalogers.
+// It is the primary way a cataloger finds files and their contents, regardless of whether the underlying
+// source is a container image, a directory, or something else.
+type FileResolver interface {
+	HasLocation(Location) bool
+	HasPath(string) bool
+	FilesByPath(paths ...string) ([]Location, error)
+	FilesByGlob(patterns ...string) ([]Location, error)
+
+	// FilesByPathCtx and FilesByGlobCtx are context-aware variants of FilesByPath and FilesByGlob for callers
+	// that want to resolve many paths or patterns concurrently and be able to cancel the work partway through.
+	FilesByPathCtx(ctx context.Context, paths ...string) ([]Location, error)
+	FilesByGlobCtx(ctx context.Context, patterns ...string) ([]Location, error)
+
+	RelativeFileByPath(_ Location, path string) *Location
+	MultipleFileContentsByLocation([]Location) (map[Location]io.ReadCloser, error)
+	FileContentsByLocation(Location) (io.ReadCloser, error)
+
+	// FilesByCoordinates returns a Location for each of the given Coordinates that is still present in the
+	// source, mirroring FilesByPath but keyed on the stable identity used across scans rather than a raw path.
+	FilesByCoordinates(coordinates ...Coordinates) ([]Location, error)
+
+	// AllCoordinates returns the Coordinates of every file known to the source, suitable for callers that need
+	// to enumerate the full file set (e.g. to compute what was not cataloged).
+	AllCoordinates() CoordinateSet
+
+	// WalkFiles streams a Location to fn for every match of the given glob patterns, without accumulating
+	// results in memory. The walk stops early, returning ctx.Err(), if ctx is cancelled.
+	WalkFiles(ctx context.Context, patterns []string, fn func(Location) error) error
+
+	// FilesByGlobStream is a channel-based variant of WalkFiles for callers that prefer to range over
+	// results instead of supplying a callback.
+	FilesByGlobStream(ctx context.Context, patterns ...string) (<-chan Location, <-chan error)
+}
@@ -0,0 +1,313 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+var _ FileResolver = (*imageAllLayersResolver)(nil)
+
+// imageAllLayersResolver implements path and content access for the AllLayers source option for container
+// image data sources, returning a Location for every layer that contains a match rather than collapsing
+// layers down to a single squashed view.
+type imageAllLayersResolver struct {
+	img    *image.Image
+	layers []*image.Layer
+}
+
+// newAllLayersResolver returns a new resolver from the perspective of all layers of the given image.
+func newAllLayersResolver(img *image.Image) (*imageAllLayersResolver, error) {
+	if len(img.Layers) == 0 {
+		return nil, fmt.Errorf("the image does not have any layers")
+	}
+
+	return &imageAllLayersResolver{
+		img:    img,
+		layers: img.Layers,
+	}, nil
+}
+
+func (r *imageAllLayersResolver) HasLocation(l Location) bool {
+	if l.ref.ID() == 0 {
+		return false
+	}
+	for _, layer := range r.layers {
+		if layer.Tree.HasPath(file.Path(l.RealPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPath indicates if the given path exists in any layer of the underlying source.
+func (r *imageAllLayersResolver) HasPath(path string) bool {
+	for _, layer := range r.layers {
+		if layer.Tree.HasPath(file.Path(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilesByPath returns a Location for every layer that resolves the given paths to a non-directory file.
+func (r *imageAllLayersResolver) FilesByPath(paths ...string) ([]Location, error) {
+	return r.FilesByPathCtx(context.Background(), paths...)
+}
+
+// FilesByPathCtx is a context-aware variant of FilesByPath. Unlike the squashed resolver, matches are checked
+// sequentially across layers rather than behind a worker pool, since all-layers lookups are already the slow
+// path relative to the squashed tree and cancellation is the main thing callers need here.
+func (r *imageAllLayersResolver) FilesByPathCtx(ctx context.Context, paths ...string) ([]Location, error) {
+	var locations []Location
+	for _, path := range paths {
+		for _, layer := range r.layers {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+
+			_, ref, err := layer.Tree.File(file.Path(path), filetree.FollowBasenameLinks)
+			if err != nil {
+				return nil, err
+			}
+			if ref == nil {
+				continue
+			}
+
+			resolvedRef, err := r.resolveRef(*ref)
+			if err != nil {
+				return nil, err
+			}
+			if resolvedRef == nil {
+				continue
+			}
+
+			locations = append(locations, NewLocationFromImage(path, *resolvedRef, r.img))
+		}
+	}
+	return locations, nil
+}
+
+// FilesByGlob returns a Location for every layer that has a match for the given glob patterns.
+func (r *imageAllLayersResolver) FilesByGlob(patterns ...string) ([]Location, error) {
+	return r.FilesByGlobCtx(context.Background(), patterns...)
+}
+
+// FilesByGlobCtx is a context-aware variant of FilesByGlob, sequential across layers for the same reason as
+// FilesByPathCtx.
+func (r *imageAllLayersResolver) FilesByGlobCtx(ctx context.Context, patterns ...string) ([]Location, error) {
+	var locations []Location
+	for _, pattern := range patterns {
+		for _, layer := range r.layers {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+
+			results, err := layer.Tree.FilesByGlob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve files by glob (%s): %w", pattern, err)
+			}
+
+			for _, result := range results {
+				resolvedRef, err := r.resolveRef(result.Reference)
+				if err != nil {
+					return nil, err
+				}
+				if resolvedRef == nil {
+					continue
+				}
+
+				locations = append(locations, NewLocationFromImage(string(result.MatchPath), *resolvedRef, r.img))
+			}
+		}
+	}
+	return locations, nil
+}
+
+// resolveRef filters out directories and the synthetic root entry for a reference found in a layer's tree.
+func (r *imageAllLayersResolver) resolveRef(ref file.Reference) (*file.Reference, error) {
+	if ref.RealPath == "/" {
+		return nil, nil
+	}
+	if r.img.FileCatalog.Exists(ref) {
+		metadata, err := r.img.FileCatalog.Get(ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get file metadata for path=%q: %w", ref.RealPath, err)
+		}
+		if metadata.Metadata.IsDir {
+			return nil, nil
+		}
+	}
+	return &ref, nil
+}
+
+// RelativeFileByPath fetches a single file at the given path from any layer.
+func (r *imageAllLayersResolver) RelativeFileByPath(_ Location, path string) *Location {
+	locations, err := r.FilesByPath(path)
+	if err != nil || len(locations) == 0 {
+		return nil
+	}
+	return &locations[0]
+}
+
+// MultipleFileContentsByLocation returns the file contents for all given locations.
+func (r *imageAllLayersResolver) MultipleFileContentsByLocation(locations []Location) (map[Location]io.ReadCloser, error) {
+	return mapLocationRefs(r.img.MultipleFileContentsByRef, locations)
+}
+
+// FileContentsByLocation fetches file contents for a single location's file reference.
+func (r *imageAllLayersResolver) FileContentsByLocation(location Location) (io.ReadCloser, error) {
+	return r.img.FileContentsByRef(location.ref)
+}
+
+// WalkFiles streams a Location to fn for every match of the given glob patterns across all layers, without
+// accumulating results in memory. Deduplication is done against a bounded LRU of ref IDs, as in the squashed
+// resolver's WalkFiles.
+func (r *imageAllLayersResolver) WalkFiles(ctx context.Context, patterns []string, fn func(Location) error) error {
+	seen, err := lru.New[file.ID, struct{}](refIDCacheSize)
+	if err != nil {
+		return fmt.Errorf("unable to create file ID cache: %w", err)
+	}
+
+	for _, pattern := range patterns {
+		for _, layer := range r.layers {
+			results, err := layer.Tree.FilesByGlob(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to resolve files by glob (%s): %w", pattern, err)
+			}
+
+			for _, result := range results {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				default:
+				}
+
+				resolvedRef, err := r.resolveRef(result.Reference)
+				if err != nil {
+					return err
+				}
+				if resolvedRef == nil {
+					continue
+				}
+
+				if _, ok := seen.Get(resolvedRef.ID()); ok {
+					continue
+				}
+				seen.Add(resolvedRef.ID(), struct{}{})
+
+				if err := fn(NewLocationFromImage(string(result.MatchPath), *resolvedRef, r.img)); err != nil {
+					return fmt.Errorf("failed to process file (path=%q): %w", result.MatchPath, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// FilesByGlobStream is a channel-based variant of WalkFiles for callers that prefer to range over results.
+func (r *imageAllLayersResolver) FilesByGlobStream(ctx context.Context, patterns ...string) (<-chan Location, <-chan error) {
+	locations := make(chan Location)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(locations)
+		defer close(errc)
+
+		err := r.WalkFiles(ctx, patterns, func(l Location) error {
+			select {
+			case locations <- l:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return locations, errc
+}
+
+// FilesByCoordinates returns a Location for each given Coordinates. When a Coordinates has a FileSystemID, it
+// is resolved directly against the layer with that digest rather than every layer that happens to have the
+// same path, so a caller asking for one specific version of a file doesn't silently get every version back. A
+// Coordinates with no FileSystemID falls back to the same all-layers search FilesByPath does.
+func (r *imageAllLayersResolver) FilesByCoordinates(coordinates ...Coordinates) ([]Location, error) {
+	var locations []Location
+	for _, c := range coordinates {
+		if c.FileSystemID == "" {
+			matches, err := r.FilesByPath(c.RealPath)
+			if err != nil {
+				return nil, err
+			}
+			locations = append(locations, matches...)
+			continue
+		}
+
+		layer := r.layerByDigest(c.FileSystemID)
+		if layer == nil {
+			continue
+		}
+
+		_, ref, err := layer.Tree.File(file.Path(c.RealPath), filetree.FollowBasenameLinks)
+		if err != nil {
+			return nil, err
+		}
+		if ref == nil {
+			continue
+		}
+
+		resolvedRef, err := r.resolveRef(*ref)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedRef == nil {
+			continue
+		}
+
+		locations = append(locations, NewLocationFromImage(c.RealPath, *resolvedRef, r.img))
+	}
+	return locations, nil
+}
+
+// layerByDigest returns the layer with the given digest, or nil if no layer matches.
+func (r *imageAllLayersResolver) layerByDigest(digest string) *image.Layer {
+	for _, layer := range r.layers {
+		if layer.Metadata.Digest == digest {
+			return layer
+		}
+	}
+	return nil
+}
+
+// AllCoordinates returns the Coordinates of every non-directory file known to any layer of the image, with
+// FileSystemID populated from the owning layer's digest.
+func (r *imageAllLayersResolver) AllCoordinates() CoordinateSet {
+	result := NewCoordinateSet()
+	for _, layer := range r.layers {
+		for _, ref := range layer.Tree.AllFiles() {
+			resolvedRef, err := r.resolveRef(ref)
+			if err != nil || resolvedRef == nil {
+				continue
+			}
+			result.Add(Coordinates{
+				RealPath:     string(resolvedRef.RealPath),
+				FileSystemID: layer.Metadata.Digest,
+			})
+		}
+	}
+	return result
+}
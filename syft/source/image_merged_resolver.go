@@ -0,0 +1,376 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+var _ FileResolver = (*mergedImageResolver)(nil)
+
+// annotation keys set on Locations returned by the mergedImageResolver, describing where a file came from
+// relative to the image's layers and whether a newer layer shadowed it in the squashed representation.
+const (
+	annotationContributingLayers = "source:contributing-layers"
+	annotationShadowedInSquash   = "source:shadowed-in-squash"
+)
+
+// mergedImageResolver implements path and content access by unioning the Squashed and AllLayers perspectives
+// of a container image, annotating each Location with which layer(s) contributed it and whether a later layer
+// shadowed (deleted or overwrote) it in the squash. This gives catalogers visibility into files that only
+// exist in intermediate layers without requiring callers to stand up both resolvers and reconcile results.
+type mergedImageResolver struct {
+	img       *image.Image
+	squash    *imageSquashResolver
+	allLayers *imageAllLayersResolver
+}
+
+// newMergedImageResolver returns a new resolver from the combined perspective of the squashed and all-layers
+// representations of the given image. Any ResolverOption is forwarded to the underlying squash resolver (e.g.
+// to control how many paths or glob results it resolves concurrently).
+func newMergedImageResolver(img *image.Image, opts ...ResolverOption) (*mergedImageResolver, error) {
+	squash, err := newImageSquashResolver(img, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create squash resolver for merged resolver: %w", err)
+	}
+
+	allLayers, err := newAllLayersResolver(img)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create all-layers resolver for merged resolver: %w", err)
+	}
+
+	return &mergedImageResolver{
+		img:       img,
+		squash:    squash,
+		allLayers: allLayers,
+	}, nil
+}
+
+func (r *mergedImageResolver) HasLocation(l Location) bool {
+	return r.squash.HasLocation(l) || r.allLayers.HasLocation(l)
+}
+
+// HasPath indicates if the given path exists in either perspective of the underlying source.
+func (r *mergedImageResolver) HasPath(path string) bool {
+	return r.squash.HasPath(path) || r.allLayers.HasPath(path)
+}
+
+// FilesByPath returns the union of squashed and all-layers matches for the given paths, annotated with
+// provenance and deduplicated by content digest when available, falling back to (layer, ref) otherwise.
+func (r *mergedImageResolver) FilesByPath(paths ...string) ([]Location, error) {
+	squashResults, err := r.squash.FilesByPath(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve squashed files by path: %w", err)
+	}
+
+	allLayersResults, err := r.allLayers.FilesByPath(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve all-layers files by path: %w", err)
+	}
+
+	return r.merge(squashResults, allLayersResults), nil
+}
+
+// FilesByPathCtx is a context-aware variant of FilesByPath, resolving the squashed and all-layers perspectives
+// concurrently before merging their results.
+func (r *mergedImageResolver) FilesByPathCtx(ctx context.Context, paths ...string) ([]Location, error) {
+	g, ctx := errgroup.WithContext(ctx)
+
+	var squashResults, allLayersResults []Location
+	g.Go(func() (err error) {
+		squashResults, err = r.squash.FilesByPathCtx(ctx, paths...)
+		return err
+	})
+	g.Go(func() (err error) {
+		allLayersResults, err = r.allLayers.FilesByPathCtx(ctx, paths...)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("unable to resolve files by path: %w", err)
+	}
+
+	return r.merge(squashResults, allLayersResults), nil
+}
+
+// FilesByGlob returns the union of squashed and all-layers glob matches, annotated and deduplicated as in
+// FilesByPath.
+func (r *mergedImageResolver) FilesByGlob(patterns ...string) ([]Location, error) {
+	squashResults, err := r.squash.FilesByGlob(patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve squashed files by glob: %w", err)
+	}
+
+	allLayersResults, err := r.allLayers.FilesByGlob(patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve all-layers files by glob: %w", err)
+	}
+
+	return r.merge(squashResults, allLayersResults), nil
+}
+
+// FilesByGlobCtx is a context-aware variant of FilesByGlob, resolving the squashed and all-layers perspectives
+// concurrently before merging their results.
+func (r *mergedImageResolver) FilesByGlobCtx(ctx context.Context, patterns ...string) ([]Location, error) {
+	g, ctx := errgroup.WithContext(ctx)
+
+	var squashResults, allLayersResults []Location
+	g.Go(func() (err error) {
+		squashResults, err = r.squash.FilesByGlobCtx(ctx, patterns...)
+		return err
+	})
+	g.Go(func() (err error) {
+		allLayersResults, err = r.allLayers.FilesByGlobCtx(ctx, patterns...)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("unable to resolve files by glob: %w", err)
+	}
+
+	return r.merge(squashResults, allLayersResults), nil
+}
+
+// FilesByCoordinates returns the union of squashed and all-layers matches for the given set of Coordinates,
+// annotated and deduplicated as in FilesByPath. Each Coordinates is resolved against its own FileSystemID by
+// the underlying resolvers rather than treated as a bare path, so a caller asking for one specific layer's
+// version of a file doesn't get every layer's version back. A Coordinates with no FileSystemID is matched by
+// path alone in the all-layers perspective and matches nothing in the squashed one.
+func (r *mergedImageResolver) FilesByCoordinates(coordinates ...Coordinates) ([]Location, error) {
+	squashResults, err := r.squash.FilesByCoordinates(coordinates...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve squashed files by coordinates: %w", err)
+	}
+
+	allLayersResults, err := r.allLayers.FilesByCoordinates(coordinates...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve all-layers files by coordinates: %w", err)
+	}
+
+	return r.merge(squashResults, allLayersResults), nil
+}
+
+// AllCoordinates returns the union of Coordinates known to the squashed and all-layers perspectives of the
+// image.
+func (r *mergedImageResolver) AllCoordinates() CoordinateSet {
+	result := r.squash.AllCoordinates()
+	for _, c := range r.allLayers.AllCoordinates().ToSlice() {
+		result.Add(c)
+	}
+	return result
+}
+
+// merge combines squashed and all-layers results, annotating each Location with its contributing layer(s) and
+// whether it was shadowed (present in all-layers but not present, or present under a different ref, in the
+// squash). Locations are deduplicated by content digest when the file catalog has one recorded, otherwise by
+// (layer, ref ID).
+func (r *mergedImageResolver) merge(squashResults, allLayersResults []Location) []Location {
+	squashedByKey := make(map[string]struct{}, len(squashResults))
+	for _, l := range squashResults {
+		squashedByKey[r.dedupeKey(l)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{})
+	var merged []Location
+
+	annotate := func(l Location) Location {
+		_, inSquash := squashedByKey[r.dedupeKey(l)]
+		return withAnnotations(l, computeProvenanceAnnotations(r.layersContaining(l), inSquash))
+	}
+
+	for _, l := range squashResults {
+		key := r.dedupeKey(l)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		merged = append(merged, annotate(l))
+	}
+
+	for _, l := range allLayersResults {
+		key := r.dedupeKey(l)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		merged = append(merged, annotate(l))
+	}
+
+	return merged
+}
+
+// layersContaining returns the digests of every layer whose tree resolves the location's path to the same
+// file reference, in image layer order.
+func (r *mergedImageResolver) layersContaining(l Location) []string {
+	var layers []string
+	for _, layer := range r.img.Layers {
+		_, ref, err := layer.Tree.File(file.Path(l.RealPath))
+		if err != nil || ref == nil {
+			continue
+		}
+		if ref.ID() == l.ref.ID() {
+			layers = append(layers, layer.Metadata.Digest)
+		}
+	}
+	return layers
+}
+
+// dedupeKey returns a stable key for a Location: the file content digest when the catalog has one recorded,
+// otherwise the (layer, ref) pair.
+func (r *mergedImageResolver) dedupeKey(l Location) string {
+	if metadata, err := r.img.FileCatalog.Get(l.ref); err == nil {
+		if digest := metadata.Digests(); len(digest) > 0 {
+			return digest[0].Algorithm + ":" + digest[0].Value
+		}
+	}
+	return fmt.Sprintf("%s:%d", l.FileSystemID, l.ref.ID())
+}
+
+// computeProvenanceAnnotations builds the annotation set describing where a merged Location came from: which
+// layer(s) contributed it (sorted for determinism) and whether it was shadowed (absent) from the squash.
+func computeProvenanceAnnotations(contributingLayers []string, inSquash bool) map[string]string {
+	layers := make([]string, len(contributingLayers))
+	copy(layers, contributingLayers)
+	sort.Strings(layers)
+
+	return map[string]string{
+		annotationContributingLayers: strings.Join(layers, ","),
+		annotationShadowedInSquash:   strconv.FormatBool(!inSquash),
+	}
+}
+
+// withAnnotations returns a copy of l with the given annotations set via Location.WithAnnotation.
+func withAnnotations(l Location, annotations map[string]string) Location {
+	for k, v := range annotations {
+		l = l.WithAnnotation(k, v)
+	}
+	return l
+}
+
+// WalkFiles streams a Location to fn for every match of the given glob patterns in either perspective,
+// annotated and deduplicated as in merge, without accumulating results from both perspectives in memory
+// at once beyond the set of dedupe keys seen so far.
+func (r *mergedImageResolver) WalkFiles(ctx context.Context, patterns []string, fn func(Location) error) error {
+	var mu sync.Mutex
+	seen := make(map[string]struct{})
+
+	walk := func(resolver FileResolver, inSquash bool) error {
+		return resolver.WalkFiles(ctx, patterns, func(l Location) error {
+			mu.Lock()
+			key := r.dedupeKey(l)
+			_, dup := seen[key]
+			if !dup {
+				seen[key] = struct{}{}
+			}
+			mu.Unlock()
+			if dup {
+				return nil
+			}
+
+			return fn(withAnnotations(l, computeProvenanceAnnotations(r.layersContaining(l), inSquash)))
+		})
+	}
+
+	if err := walk(r.squash, true); err != nil {
+		return err
+	}
+	return walk(r.allLayers, false)
+}
+
+// FilesByGlobStream is a channel-based variant of WalkFiles for callers that prefer to range over results.
+func (r *mergedImageResolver) FilesByGlobStream(ctx context.Context, patterns ...string) (<-chan Location, <-chan error) {
+	locations := make(chan Location)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(locations)
+		defer close(errc)
+
+		err := r.WalkFiles(ctx, patterns, func(l Location) error {
+			select {
+			case locations <- l:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return locations, errc
+}
+
+// RelativeFileByPath fetches a single file at the given path relative to the layer squash of the given
+// reference, preferring the squashed perspective and falling back to all-layers.
+func (r *mergedImageResolver) RelativeFileByPath(l Location, path string) *Location {
+	if result := r.squash.RelativeFileByPath(l, path); result != nil {
+		return result
+	}
+	return r.allLayers.RelativeFileByPath(l, path)
+}
+
+// MultipleFileContentsByLocation returns the file contents for all given locations, dispatching each one as
+// FileContentsByLocation does (by its shadowed-in-squash and contributing-layers annotations).
+func (r *mergedImageResolver) MultipleFileContentsByLocation(locations []Location) (map[Location]io.ReadCloser, error) {
+	result := make(map[Location]io.ReadCloser)
+	for _, l := range locations {
+		contents, err := r.FileContentsByLocation(l)
+		if err != nil {
+			return nil, err
+		}
+		result[l] = contents
+	}
+	return result, nil
+}
+
+// FileContentsByLocation fetches file contents for a single location, dispatching to the squashed resolver
+// unless the location was shadowed in the squash, in which case it is read from the layer it was annotated
+// as contributed by.
+func (r *mergedImageResolver) FileContentsByLocation(location Location) (io.ReadCloser, error) {
+	shadowed, _ := location.Annotation(annotationShadowedInSquash)
+	if shadowed != "true" {
+		return r.squash.FileContentsByLocation(location)
+	}
+
+	contributingLayers, _ := location.Annotation(annotationContributingLayers)
+	layers := strings.Split(contributingLayers, ",")
+	if len(layers) == 0 || layers[0] == "" {
+		return nil, fmt.Errorf("unable to determine contributing layer for path=%q", location.RealPath)
+	}
+
+	ref, err := r.refInLayer(layers[0], location.RealPath)
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("unable to find path=%q in layer=%q", location.RealPath, layers[0])
+	}
+
+	return r.img.FileContentsByRef(*ref)
+}
+
+// refInLayer looks up the file.Reference for path within the layer whose digest matches layerDigest.
+func (r *mergedImageResolver) refInLayer(layerDigest, path string) (*file.Reference, error) {
+	for _, layer := range r.img.Layers {
+		if layer.Metadata.Digest != layerDigest {
+			continue
+		}
+		_, ref, err := layer.Tree.File(file.Path(path))
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve path=%q in layer=%q: %w", path, layerDigest, err)
+		}
+		return ref, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package source
+
+import (
+	"testing"
+)
+
+func TestComputeProvenanceAnnotations(t *testing.T) {
+	tests := []struct {
+		name               string
+		contributingLayers []string
+		inSquash           bool
+		wantLayers         string
+		wantShadowed       string
+	}{
+		{
+			name:               "present in squash, single layer",
+			contributingLayers: []string{"sha256:aaa"},
+			inSquash:           true,
+			wantLayers:         "sha256:aaa",
+			wantShadowed:       "false",
+		},
+		{
+			name:               "deleted in a later layer, only found in an earlier one",
+			contributingLayers: []string{"sha256:bbb"},
+			inSquash:           false,
+			wantLayers:         "sha256:bbb",
+			wantShadowed:       "true",
+		},
+		{
+			name:               "present in multiple layers, sorted deterministically",
+			contributingLayers: []string{"sha256:ccc", "sha256:aaa", "sha256:bbb"},
+			inSquash:           true,
+			wantLayers:         "sha256:aaa,sha256:bbb,sha256:ccc",
+			wantShadowed:       "false",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := computeProvenanceAnnotations(test.contributingLayers, test.inSquash)
+			if got[annotationContributingLayers] != test.wantLayers {
+				t.Errorf("contributing layers: got %q, want %q", got[annotationContributingLayers], test.wantLayers)
+			}
+			if got[annotationShadowedInSquash] != test.wantShadowed {
+				t.Errorf("shadowed: got %q, want %q", got[annotationShadowedInSquash], test.wantShadowed)
+			}
+		})
+	}
+}
+
+func TestWithAnnotations_PreservesAndOverrides(t *testing.T) {
+	base := Location{Coordinates: Coordinates{RealPath: "/bin/busybox"}}
+
+	annotated := withAnnotations(base, map[string]string{
+		annotationContributingLayers: "sha256:aaa",
+		annotationShadowedInSquash:   "true",
+	})
+
+	if v, ok := annotated.Annotation(annotationShadowedInSquash); !ok || v != "true" {
+		t.Fatalf("expected shadowed annotation to be set to true, got %q (ok=%v)", v, ok)
+	}
+
+	// the original Location passed in must be left untouched
+	if _, ok := base.Annotation(annotationShadowedInSquash); ok {
+		t.Fatalf("expected original Location to be unmodified")
+	}
+
+	reAnnotated := withAnnotations(annotated, map[string]string{annotationShadowedInSquash: "false"})
+	if v, _ := reAnnotated.Annotation(annotationShadowedInSquash); v != "false" {
+		t.Fatalf("expected shadowed annotation to be overridden to false, got %q", v)
+	}
+	if v, ok := reAnnotated.Annotation(annotationContributingLayers); !ok || v != "sha256:aaa" {
+		t.Fatalf("expected unrelated annotation to be preserved, got %q (ok=%v)", v, ok)
+	}
+}
@@ -1,24 +1,51 @@
 package source
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/anchore/stereoscope/pkg/file"
 	"github.com/anchore/stereoscope/pkg/filetree"
 	"github.com/anchore/stereoscope/pkg/image"
 )
 
+// refIDCacheSize bounds the number of previously-seen file reference IDs kept by WalkFiles while streaming.
+const refIDCacheSize = 10000
+
+// defaultResolverParallelism is the pool size used by FilesByPathCtx and FilesByGlobCtx when the caller does
+// not specify one via WithResolverParallelism.
+const defaultResolverParallelism = 4
+
 var _ FileResolver = (*imageSquashResolver)(nil)
 
 // imageSquashResolver implements path and content access for the Squashed source option for container image data sources.
 type imageSquashResolver struct {
-	img  *image.Image
-	refs file.ReferenceSet
+	img         *image.Image
+	refs        file.ReferenceSet
+	parallelism int
+}
+
+// ResolverOption configures an imageSquashResolver at construction time.
+type ResolverOption func(*imageSquashResolver)
+
+// WithResolverParallelism overrides the number of paths or glob results resolved concurrently. Values less
+// than 1 are ignored, leaving the current value (defaultResolverParallelism unless an earlier option set one)
+// in place.
+func WithResolverParallelism(n int) ResolverOption {
+	return func(r *imageSquashResolver) {
+		if n > 0 {
+			r.parallelism = n
+		}
+	}
 }
 
 // newImageSquashResolver returns a new resolver from the perspective of the squashed representation for the given image.
-func newImageSquashResolver(img *image.Image) (*imageSquashResolver, error) {
+func newImageSquashResolver(img *image.Image, opts ...ResolverOption) (*imageSquashResolver, error) {
 	if img.SquashedTree() == nil {
 		return nil, fmt.Errorf("the image does not have have a squashed tree")
 	}
@@ -28,10 +55,17 @@ func newImageSquashResolver(img *image.Image) (*imageSquashResolver, error) {
 		refs.Add(r)
 	}
 
-	return &imageSquashResolver{
-		img:  img,
-		refs: refs,
-	}, nil
+	resolver := &imageSquashResolver{
+		img:         img,
+		refs:        refs,
+		parallelism: defaultResolverParallelism,
+	}
+
+	for _, opt := range opts {
+		opt(resolver)
+	}
+
+	return resolver, nil
 }
 
 func (r *imageSquashResolver) HasLocation(l Location) bool {
@@ -46,89 +80,305 @@ func (r *imageSquashResolver) HasPath(path string) bool {
 	return r.img.SquashedTree().HasPath(file.Path(path))
 }
 
+// resolveSquashedRef filters out directories and resolves symlinks for a single reference found in the
+// squashed tree, returning a nil reference (with no error) when the reference should be skipped. This is the
+// common core shared by FilesByPathCtx, FilesByGlobCtx, and WalkFiles.
+func (r *imageSquashResolver) resolveSquashedRef(ref file.Reference) (*file.Reference, error) {
+	// don't consider directories (special case: there is no path information for /)
+	if ref.RealPath == "/" {
+		return nil, nil
+	} else if r.img.FileCatalog.Exists(ref) {
+		metadata, err := r.img.FileCatalog.Get(ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get file metadata for path=%q: %w", ref.RealPath, err)
+		}
+		if metadata.Metadata.IsDir {
+			return nil, nil
+		}
+	}
+
+	// a file may be a symlink, process it as such and resolve it
+	return r.img.ResolveLinkByImageSquash(ref)
+}
+
+// uniqueLocations is a mutex-guarded accumulator shared by goroutines resolving paths or glob matches
+// concurrently, deduplicating by the resolved file.Reference.
+type uniqueLocations struct {
+	mu        sync.Mutex
+	seenRefs  file.ReferenceSet
+	locations []Location
+}
+
+func newUniqueLocations() *uniqueLocations {
+	return &uniqueLocations{seenRefs: file.NewFileReferenceSet()}
+}
+
+// add records loc under ref if ref has not already been seen, and reports whether it was added.
+func (u *uniqueLocations) add(ref file.Reference, loc Location) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.seenRefs.Contains(ref) {
+		return false
+	}
+	u.seenRefs.Add(ref)
+	u.locations = append(u.locations, loc)
+	return true
+}
+
+// boundedResolve runs fn once per item behind a single flat worker pool sized by parallelism, stopping early
+// on the first error (including ctx cancellation). This is the one pool shared by FilesByPathCtx and
+// FilesByGlobCtx: neither may spawn more work onto a limited errgroup from inside a goroutine that group is
+// already running, since once every slot is held by such a goroutine, none of them can ever free a slot for
+// the work they're trying to enqueue. Flattening the input to a single slice up front, as both callers do,
+// avoids that nesting entirely.
+func boundedResolve[T any](ctx context.Context, parallelism int, items []T, fn func(ctx context.Context, item T) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallelism)
+
+	for _, item := range items {
+		item := item
+		g.Go(func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			return fn(ctx, item)
+		})
+	}
+
+	return g.Wait()
+}
+
 // FilesByPath returns all file.References that match the given paths within the squashed representation of the image.
 func (r *imageSquashResolver) FilesByPath(paths ...string) ([]Location, error) {
-	uniqueFileIDs := file.NewFileReferenceSet()
-	uniqueLocations := make([]Location, 0)
+	return r.FilesByPathCtx(context.Background(), paths...)
+}
+
+// FilesByPathCtx is a context-aware variant of FilesByPath that resolves each path concurrently behind a
+// worker pool sized by WithResolverParallelism.
+func (r *imageSquashResolver) FilesByPathCtx(ctx context.Context, paths ...string) ([]Location, error) {
+	results := newUniqueLocations()
 
-	for _, path := range paths {
+	err := boundedResolve(ctx, r.parallelism, paths, func(_ context.Context, path string) error {
 		tree := r.img.SquashedTree()
 		_, ref, err := tree.File(file.Path(path), filetree.FollowBasenameLinks)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if ref == nil {
 			// no file found, keep looking through layers
-			continue
+			return nil
 		}
 
-		// don't consider directories (special case: there is no path information for /)
-		if ref.RealPath == "/" {
-			continue
-		} else if r.img.FileCatalog.Exists(*ref) {
-			metadata, err := r.img.FileCatalog.Get(*ref)
-			if err != nil {
-				return nil, fmt.Errorf("unable to get file metadata for path=%q: %w", ref.RealPath, err)
-			}
-			if metadata.Metadata.IsDir {
-				continue
-			}
+		resolvedRef, err := r.resolveSquashedRef(*ref)
+		if err != nil {
+			return err
+		}
+		if resolvedRef == nil {
+			return nil
 		}
 
-		// a file may be a symlink, process it as such and resolve it
-		resolvedRef, err := r.img.ResolveLinkByImageSquash(*ref)
+		results.add(*resolvedRef, NewLocationFromImage(path, *resolvedRef, r.img))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results.locations, nil
+}
+
+// FilesByGlob returns all file.References that match the given path glob pattern within the squashed representation of the image.
+func (r *imageSquashResolver) FilesByGlob(patterns ...string) ([]Location, error) {
+	return r.FilesByGlobCtx(context.Background(), patterns...)
+}
+
+// globMatch flattens a single glob match down to the fields resolution needs, so the match-level pool below
+// doesn't need to nest inside the pattern-level loop.
+type globMatch struct {
+	ref       file.Reference
+	matchPath file.Path
+}
+
+// FilesByGlobCtx is a context-aware variant of FilesByGlob. Pattern matching against the squashed tree is
+// cheap and done up front sequentially; the per-match symlink resolution and catalog lookups, which are the
+// expensive part, are then parallelized behind a single flat worker pool. Nesting a limited errgroup inside
+// itself (one pool for patterns, spawning more work onto the same pool for matches) deadlocks once the number
+// of patterns reaches the pool size, since every slot ends up held by a pattern-level goroutine blocked
+// waiting for a slot to launch its own match-level goroutine; collecting matches first avoids that entirely.
+func (r *imageSquashResolver) FilesByGlobCtx(ctx context.Context, patterns ...string) ([]Location, error) {
+	var matches []globMatch
+	for _, pattern := range patterns {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		globResults, err := r.img.SquashedTree().FilesByGlob(pattern)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve link from img (ref=%+v): %w", ref, err)
+			return nil, fmt.Errorf("failed to resolve files by glob (%s): %w", pattern, err)
 		}
+		for _, result := range globResults {
+			matches = append(matches, globMatch{ref: result.Reference, matchPath: result.MatchPath})
+		}
+	}
 
-		if resolvedRef != nil && !uniqueFileIDs.Contains(*resolvedRef) {
-			uniqueFileIDs.Add(*resolvedRef)
-			uniqueLocations = append(uniqueLocations, NewLocationFromImage(path, *resolvedRef, r.img))
+	results := newUniqueLocations()
+
+	err := boundedResolve(ctx, r.parallelism, matches, func(_ context.Context, match globMatch) error {
+		resolvedRef, err := r.resolveSquashedRef(match.ref)
+		if err != nil {
+			return err
 		}
+		if resolvedRef == nil {
+			return nil
+		}
+
+		results.add(*resolvedRef, NewLocationFromImage(string(match.matchPath), *resolvedRef, r.img))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return uniqueLocations, nil
+	return results.locations, nil
 }
 
-// FilesByGlob returns all file.References that match the given path glob pattern within the squashed representation of the image.
-func (r *imageSquashResolver) FilesByGlob(patterns ...string) ([]Location, error) {
-	uniqueFileIDs := file.NewFileReferenceSet()
-	uniqueLocations := make([]Location, 0)
+// WalkFiles streams a Location to fn for every match of the given glob patterns as the squashed tree is
+// walked, instead of accumulating results in a slice, bounding memory use on very large images. Deduplication
+// is done against a bounded LRU of ref IDs rather than an unbounded set for the same reason.
+func (r *imageSquashResolver) WalkFiles(ctx context.Context, patterns []string, fn func(Location) error) error {
+	seen, err := lru.New[file.ID, struct{}](refIDCacheSize)
+	if err != nil {
+		return fmt.Errorf("unable to create file ID cache: %w", err)
+	}
 
 	for _, pattern := range patterns {
 		results, err := r.img.SquashedTree().FilesByGlob(pattern)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve files by glob (%s): %w", pattern, err)
+			return fmt.Errorf("failed to resolve files by glob (%s): %w", pattern, err)
 		}
 
 		for _, result := range results {
-			// don't consider directories (special case: there is no path information for /)
-			if result.MatchPath == "/" {
-				continue
-			} else if r.img.FileCatalog.Exists(result.Reference) {
-				metadata, err := r.img.FileCatalog.Get(result.Reference)
-				if err != nil {
-					return nil, fmt.Errorf("unable to get file metadata for path=%q: %w", result.MatchPath, err)
-				}
-				if metadata.Metadata.IsDir {
-					continue
-				}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
 			}
 
-			resolvedLocations, err := r.FilesByPath(string(result.MatchPath))
+			resolvedRef, err := r.resolveSquashedRef(result.Reference)
 			if err != nil {
-				return nil, fmt.Errorf("failed to find files by path (result=%+v): %w", result, err)
+				return err
+			}
+			if resolvedRef == nil {
+				continue
 			}
-			for _, resolvedLocation := range resolvedLocations {
-				if !uniqueFileIDs.Contains(resolvedLocation.ref) {
-					uniqueFileIDs.Add(resolvedLocation.ref)
-					uniqueLocations = append(uniqueLocations, resolvedLocation)
-				}
+
+			if _, ok := seen.Get(resolvedRef.ID()); ok {
+				continue
+			}
+			seen.Add(resolvedRef.ID(), struct{}{})
+
+			if err := fn(NewLocationFromImage(string(result.MatchPath), *resolvedRef, r.img)); err != nil {
+				return fmt.Errorf("failed to process file (path=%q): %w", result.MatchPath, err)
 			}
 		}
 	}
 
-	return uniqueLocations, nil
+	return nil
+}
+
+// FilesByGlobStream is a channel-based variant of WalkFiles for callers that prefer to range over results.
+func (r *imageSquashResolver) FilesByGlobStream(ctx context.Context, patterns ...string) (<-chan Location, <-chan error) {
+	locations := make(chan Location)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(locations)
+		defer close(errc)
+
+		err := r.WalkFiles(ctx, patterns, func(l Location) error {
+			select {
+			case locations <- l:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return locations, errc
+}
+
+// FilesByCoordinates returns all file.References that match the given set of Coordinates within the squashed
+// representation of the image. The squashed view only ever has a single FileSystemID per path (there is only
+// one layer digest recorded per file in the catalog), so a Coordinates whose FileSystemID doesn't match what
+// the squash actually has recorded for that path is dropped rather than silently returned anyway.
+func (r *imageSquashResolver) FilesByCoordinates(coordinates ...Coordinates) ([]Location, error) {
+	paths := make([]string, len(coordinates))
+	for i, c := range coordinates {
+		paths[i] = c.RealPath
+	}
+
+	locations, err := r.FilesByPath(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterByCoordinates(locations, coordinates), nil
+}
+
+// filterByCoordinates returns the subset of locations whose Coordinates are in wanted.
+func filterByCoordinates(locations []Location, wanted []Coordinates) []Location {
+	set := make(map[Coordinates]struct{}, len(wanted))
+	for _, c := range wanted {
+		set[c] = struct{}{}
+	}
+
+	filtered := make([]Location, 0, len(locations))
+	for _, l := range locations {
+		if _, ok := set[l.Coordinates]; ok {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
+// AllCoordinates returns the Coordinates of every non-directory file known to the squashed representation of
+// the image, with FileSystemID populated from the layer digest recorded in the image's file catalog.
+func (r *imageSquashResolver) AllCoordinates() CoordinateSet {
+	result := NewCoordinateSet()
+	for _, ref := range r.refs {
+		if ref.RealPath == "/" {
+			continue
+		}
+		if r.img.FileCatalog.Exists(ref) {
+			metadata, err := r.img.FileCatalog.Get(ref)
+			if err != nil || metadata.Metadata.IsDir {
+				continue
+			}
+		}
+		result.Add(r.coordinatesForRef(ref))
+	}
+	return result
+}
+
+// coordinatesForRef builds the Coordinates for a single file.Reference, looking up the contributing layer
+// digest from the image's file catalog when available.
+func (r *imageSquashResolver) coordinatesForRef(ref file.Reference) Coordinates {
+	var fileSystemID string
+	if metadata, err := r.img.FileCatalog.Get(ref); err == nil {
+		fileSystemID = metadata.Layer
+	}
+	return Coordinates{
+		RealPath:     string(ref.RealPath),
+		FileSystemID: fileSystemID,
+	}
 }
 
 // RelativeFileByPath fetches a single file at the given path relative to the layer squash of the given reference.
@@ -156,4 +406,4 @@ func (r *imageSquashResolver) MultipleFileContentsByLocation(locations []Locatio
 // If the path does not exist an error is returned.
 func (r *imageSquashResolver) FileContentsByLocation(location Location) (io.ReadCloser, error) {
 	return r.img.FileContentsByRef(location.ref)
-}
\ No newline at end of file
+}
@@ -0,0 +1,113 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func TestWithResolverParallelism(t *testing.T) {
+	r := &imageSquashResolver{parallelism: defaultResolverParallelism}
+
+	WithResolverParallelism(10)(r)
+	if r.parallelism != 10 {
+		t.Fatalf("expected parallelism=10, got %d", r.parallelism)
+	}
+
+	// non-positive values are ignored, leaving the previous value in place
+	WithResolverParallelism(0)(r)
+	if r.parallelism != 10 {
+		t.Fatalf("expected parallelism to remain 10, got %d", r.parallelism)
+	}
+}
+
+func TestUniqueLocations_Add_Concurrent(t *testing.T) {
+	const workers = 50
+	const distinctRefs = 20
+
+	// every worker races to add the exact same set of references, simulating multiple patterns/paths
+	// resolving to the same underlying file concurrently; only one Location per ref should survive.
+	refs := make([]file.Reference, distinctRefs)
+	for i := range refs {
+		refs[i] = file.NewFileReference(file.Path(fmt.Sprintf("/usr/bin/tool-%d", i)))
+	}
+
+	results := newUniqueLocations()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, ref := range refs {
+				results.add(ref, Location{Coordinates: Coordinates{RealPath: string(ref.RealPath)}})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(results.locations) != distinctRefs {
+		t.Fatalf("expected %d unique locations, got %d", distinctRefs, len(results.locations))
+	}
+}
+
+// TestBoundedResolve_DoesNotDeadlock guards the exact shape FilesByPathCtx and FilesByGlobCtx share: a single
+// flat pool over all items. An earlier version of FilesByGlobCtx instead nested a g.Go call for each pattern's
+// matches inside a g.Go call for the pattern itself, on the very same limited errgroup — once the number of
+// patterns reached the pool size, every slot was held by a pattern-level goroutine blocked trying to acquire a
+// slot for its own match-level goroutine, and none could ever free up. Such a hang in boundedResolve makes this
+// test fail by its timeout rather than by assertion; the test does not call FilesByGlobCtx itself.
+func TestBoundedResolve_DoesNotDeadlock(t *testing.T) {
+	const parallelism = 4
+	const itemCount = 9 // more than parallelism, so some items must queue behind others
+
+	items := make([]int, itemCount)
+	for i := range items {
+		items[i] = i
+	}
+
+	var mu sync.Mutex
+	var processed []int
+
+	done := make(chan error, 1)
+	go func() {
+		done <- boundedResolve(context.Background(), parallelism, items, func(_ context.Context, item int) error {
+			mu.Lock()
+			processed = append(processed, item)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("boundedResolve did not return within timeout, possible deadlock")
+	}
+
+	if len(processed) != itemCount {
+		t.Fatalf("expected %d items processed, got %d", itemCount, len(processed))
+	}
+}
+
+func TestFilterByCoordinates(t *testing.T) {
+	a := Location{Coordinates: Coordinates{RealPath: "/etc/foo", FileSystemID: "sha256:aaa"}}
+	b := Location{Coordinates: Coordinates{RealPath: "/etc/foo", FileSystemID: "sha256:bbb"}}
+	c := Location{Coordinates: Coordinates{RealPath: "/etc/bar", FileSystemID: "sha256:aaa"}}
+
+	filtered := filterByCoordinates([]Location{a, b, c}, []Coordinates{a.Coordinates})
+
+	if len(filtered) != 1 {
+		t.Fatalf("expected exactly 1 location, got %d", len(filtered))
+	}
+	if filtered[0].Coordinates != a.Coordinates {
+		t.Fatalf("expected %v, got %v", a.Coordinates, filtered[0].Coordinates)
+	}
+}
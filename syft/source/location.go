@@ -0,0 +1,90 @@
+package source
+
+import (
+	"io"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// Location represents a single file resolved from a source, identified by the Coordinates of the filesystem
+// it was found in plus the path it was originally requested under. Location is intentionally kept comparable
+// (no maps or slices as direct fields) so that it can be used as a map key and within sets; auxiliary,
+// non-identity metadata is carried behind the annotations pointer instead of a plain map field.
+type Location struct {
+	Coordinates
+	// VirtualPath is the path originally requested from the resolver, before any symlink resolution (this
+	// differs from Coordinates.RealPath when the requested path is a symlink).
+	VirtualPath string `json:"accessPath,omitempty"`
+
+	ref         file.Reference
+	annotations *locationAnnotations
+}
+
+// locationAnnotations holds auxiliary key/value metadata about a Location, such as provenance set by the
+// mergedImageResolver. It exists so that annotations can be attached without making Location itself
+// non-comparable (a plain map field would prevent Location from being used as a map key).
+type locationAnnotations struct {
+	values map[string]string
+}
+
+// Annotation returns the value of the given annotation key on this Location, and whether it was set.
+func (l Location) Annotation(key string) (string, bool) {
+	if l.annotations == nil {
+		return "", false
+	}
+	v, ok := l.annotations.values[key]
+	return v, ok
+}
+
+// WithAnnotation returns a copy of l with the given annotation key set to value, leaving l unmodified.
+func (l Location) WithAnnotation(key, value string) Location {
+	values := make(map[string]string)
+	if l.annotations != nil {
+		for k, v := range l.annotations.values {
+			values[k] = v
+		}
+	}
+	values[key] = value
+	l.annotations = &locationAnnotations{values: values}
+	return l
+}
+
+// NewLocationFromImage creates a new Location for the given path within the given image, resolved against
+// the provided file.Reference.
+func NewLocationFromImage(responsePath string, ref file.Reference, img *image.Image) Location {
+	fileSystemID := img.Metadata.ID
+	if metadata, err := img.FileCatalog.Get(ref); err == nil && metadata.Layer != "" {
+		fileSystemID = metadata.Layer
+	}
+
+	return Location{
+		Coordinates: Coordinates{
+			RealPath:     string(ref.RealPath),
+			FileSystemID: fileSystemID,
+		},
+		VirtualPath: responsePath,
+		ref:         ref,
+	}
+}
+
+// mapLocationRefs applies the given by-ref content accessor to each location's file.Reference, returning the
+// results keyed by the original Location.
+func mapLocationRefs(contentsByRef func(refs ...file.Reference) (map[file.Reference]io.ReadCloser, error), locations []Location) (map[Location]io.ReadCloser, error) {
+	refs := make([]file.Reference, len(locations))
+	for i, l := range locations {
+		refs[i] = l.ref
+	}
+
+	contentsByRefResult, err := contentsByRef(refs...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[Location]io.ReadCloser, len(locations))
+	for _, l := range locations {
+		result[l] = contentsByRefResult[l.ref]
+	}
+
+	return result, nil
+}